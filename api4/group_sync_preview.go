@@ -0,0 +1,57 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package api4
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+func (api *API) InitLdapGroupSyncPreview() {
+	api.BaseRoutes.LDAP.Handle("/groups/sync/preview", api.ApiSessionRequired(previewLdapGroupsSync)).Methods("POST")
+}
+
+// previewLdapGroupsSync computes the membership adds and removes the next LDAP group sync run would make without
+// mutating anything, so an admin can review a misconfigured group-constrained team or channel before running the
+// real job. Passing syncable_id and syncable_type additionally previews the role promotions/demotions
+// SyncSyncableRoles would make for that one team or channel, since role sync is per-syncable rather than global.
+func previewLdapGroupsSync(c *Context, w http.ResponseWriter, r *http.Request) {
+	if !c.App.SessionHasPermissionTo(c.App.Session(), model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	since, _ := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+
+	plan, appErr := c.App.PlanDefaultMemberships(since)
+	if appErr != nil {
+		c.Err = appErr
+		return
+	}
+
+	removals, appErr := c.App.PlanGroupConstrainedRemovals()
+	if appErr != nil {
+		c.Err = appErr
+		return
+	}
+
+	plan.TeamMembersToRemove = removals.TeamMembersToRemove
+	plan.ChannelMembersToRemove = removals.ChannelMembersToRemove
+
+	if syncableID := r.URL.Query().Get("syncable_id"); syncableID != "" {
+		syncableType := model.GroupSyncableType(r.URL.Query().Get("syncable_type"))
+
+		rolePlan, appErr := c.App.PlanSyncableRoles(syncableID, syncableType)
+		if appErr != nil {
+			c.Err = appErr
+			return
+		}
+
+		plan.RoleAssignments = rolePlan.RoleAssignments
+	}
+
+	w.Write(plan.ToJson())
+}
@@ -6,101 +6,457 @@ package app
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/mattermost/mattermost-server/v5/mlog"
 	"github.com/mattermost/mattermost-server/v5/model"
-	"github.com/mattermost/mattermost-server/v5/store"
 )
 
+// groupSyncPageSize bounds how many pending group membership rows CreateDefaultMemberships pulls per page, so a
+// group with tens of thousands of members is reconciled in bounded-size batches instead of one giant slice.
+const groupSyncPageSize = 200
+
+// SyncDefaultMemberships runs CreateDefaultMemberships starting from the last persisted group sync checkpoint, so
+// the scheduled LDAP/group sync job doesn't need to track "since" itself: a run that stopped early (crash, deploy)
+// resumes from where the previous run left off instead of rescanning every group member from the beginning.
+func (a *App) SyncDefaultMemberships() (*model.SyncResult, error) {
+	return a.CreateDefaultMemberships(a.groupSyncCheckpoint())
+}
+
 // CreateDefaultMemberships adds users to teams and channels based on their group memberships and how those groups are
-// configured to sync with teams and channels for group members on or after the given timestamp.
-func (a *App) CreateDefaultMemberships(since int64) error {
-	teamMembers, appErr := a.TeamMembersToAdd(since)
-	if appErr != nil {
-		return appErr
+// configured to sync with teams and channels for group members on or after the given timestamp. Pending changes are
+// paged and grouped by team/channel so each group can be reconciled with a single batch insert, and disjoint
+// teams/channels are processed concurrently. A failure adding one user does not stop the run; failures are
+// accumulated onto the returned SyncResult, and once each page is fully processed the high-water mark of the
+// members actually processed in that page is persisted so a rerun resumes from there instead of from since.
+func (a *App) CreateDefaultMemberships(since int64) (*model.SyncResult, error) {
+	result := model.NewSyncResult()
+	adminIDs := a.systemAdminIDs()
+
+	if err := a.syncTeamMemberships(since, result, adminIDs); err != nil {
+		return result, err
 	}
 
-	for _, userTeam := range teamMembers {
-		_, err := a.AddTeamMember(userTeam.TeamID, userTeam.UserID)
-		if err != nil {
-			return err
+	if err := a.syncChannelMemberships(since, result, adminIDs); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// syncTeamMemberships pages through TeamMembersToAdd, groups each page by TeamID, and adds the missing members of
+// each team in a single batch insert, with disjoint teams processed by a bounded worker pool. mu is held only
+// around result mutation, not around the publish/audit side effects or the store call, so disjoint teams keep
+// running concurrently.
+func (a *App) syncTeamMemberships(since int64, result *model.SyncResult, adminIDs map[string]bool) *model.AppError {
+	var cursor *model.GroupMemberCursor
+
+	for {
+		page, nextCursor, appErr := a.TeamMembersToAdd(since, cursor, groupSyncPageSize)
+		if appErr != nil {
+			return appErr
+		}
+		if len(page) == 0 {
+			break
 		}
 
-		a.Log.Info("added teammember",
-			mlog.String("user_id", userTeam.UserID),
-			mlog.String("team_id", userTeam.TeamID),
-		)
-	}
+		userIDsByTeam := make(map[string][]string)
+		groupIDByTeamUser := make(map[string]map[string]string)
+		for _, userTeam := range page {
+			userIDsByTeam[userTeam.TeamID] = append(userIDsByTeam[userTeam.TeamID], userTeam.UserID)
+			if groupIDByTeamUser[userTeam.TeamID] == nil {
+				groupIDByTeamUser[userTeam.TeamID] = make(map[string]string)
+			}
+			groupIDByTeamUser[userTeam.TeamID][userTeam.UserID] = userTeam.GroupID
+		}
 
-	channelMembers, appErr := a.ChannelMembersToAdd(since)
-	if appErr != nil {
-		return appErr
+		var mu sync.Mutex
+		failedUserIDs := make(map[string]bool)
+		a.runSyncBatchesConcurrently(userIDsByTeam, func(teamID string, userIDs []string) {
+			inserted, err := a.Srv.Store.Team().AddTeamMembers(teamID, userIDs)
+			if err != nil {
+				mu.Lock()
+				for _, userID := range userIDs {
+					result.AddError(&model.SyncError{UserID: userID, TeamID: teamID, Op: model.GroupSyncOpAddTeamMember, ErrorID: err.Id, Transient: model.IsTransientSyncError(err)})
+					failedUserIDs[userID] = true
+				}
+				mu.Unlock()
+				return
+			}
+
+			mu.Lock()
+			result.Added += len(inserted)
+			result.Skipped += len(userIDs) - len(inserted)
+			mu.Unlock()
+
+			for _, userID := range inserted {
+				source := model.NewGroupSyncChangeSource(groupIDByTeamUser[teamID][userID], teamID)
+				a.publishAddedToTeamEvent(teamID, userID)
+				a.auditGroupSyncChange(userID, model.AuditActionGroupSyncAdd, source)
+				a.publishGroupMembershipChangedEvent(userID, source, adminIDs)
+				a.Log.Info("added teammember", mlog.String("user_id", userID), mlog.String("team_id", teamID))
+			}
+		})
+
+		// The watermark only advances past rows whose user succeeded (or was already a member), and never past the
+		// earliest CreateAt of a user who failed this page, even transiently, so the next run (which reads rows on or
+		// after since) sees them again instead of the checkpoint skipping past a row that was never actually processed.
+		watermark := since
+		minFailedCreateAt := int64(-1)
+		for _, userTeam := range page {
+			if failedUserIDs[userTeam.UserID] {
+				if minFailedCreateAt < 0 || userTeam.CreateAt < minFailedCreateAt {
+					minFailedCreateAt = userTeam.CreateAt
+				}
+				continue
+			}
+			if userTeam.CreateAt > watermark {
+				watermark = userTeam.CreateAt
+			}
+		}
+		if minFailedCreateAt >= 0 && minFailedCreateAt < watermark {
+			watermark = minFailedCreateAt
+		}
+		a.persistGroupSyncCheckpoint(watermark)
+
+		if nextCursor == nil {
+			break
+		}
+		cursor = nextCursor
 	}
 
-	for _, userChannel := range channelMembers {
-		channel, err := a.GetChannel(userChannel.ChannelID)
-		if err != nil {
-			return err
+	return nil
+}
+
+// syncChannelMemberships pages through ChannelMembersToAdd, groups each page by ChannelID, prefetches the
+// channels and their existing team memberships in bulk, and adds the missing team/channel members of each channel
+// in batch inserts, with disjoint channels processed by a bounded worker pool.
+func (a *App) syncChannelMemberships(since int64, result *model.SyncResult, adminIDs map[string]bool) *model.AppError {
+	var cursor *model.GroupMemberCursor
+
+	for {
+		page, nextCursor, appErr := a.ChannelMembersToAdd(since, cursor, groupSyncPageSize)
+		if appErr != nil {
+			return appErr
+		}
+		if len(page) == 0 {
+			break
 		}
 
-		tmem, err := a.GetTeamMember(channel.TeamId, userChannel.UserID)
-		if err != nil && err.Id != "store.sql_team.get_member.missing.app_error" {
+		userIDsByChannel := make(map[string][]string)
+		channelIDs := make([]string, 0, len(page))
+		seen := make(map[string]bool)
+		groupIDByChannelUser := make(map[string]map[string]string)
+		for _, userChannel := range page {
+			userIDsByChannel[userChannel.ChannelID] = append(userIDsByChannel[userChannel.ChannelID], userChannel.UserID)
+			if !seen[userChannel.ChannelID] {
+				seen[userChannel.ChannelID] = true
+				channelIDs = append(channelIDs, userChannel.ChannelID)
+			}
+			if groupIDByChannelUser[userChannel.ChannelID] == nil {
+				groupIDByChannelUser[userChannel.ChannelID] = make(map[string]string)
+			}
+			groupIDByChannelUser[userChannel.ChannelID][userChannel.UserID] = userChannel.GroupID
+		}
+
+		channels, err := a.Srv.Store.Channel().GetMany(channelIDs, true)
+		if err != nil {
 			return err
 		}
+		channelsByID := make(map[string]*model.Channel, len(channels))
+		for _, channel := range channels {
+			channelsByID[channel.Id] = channel
+		}
 
-		// First add user to team
-		if tmem == nil {
-			_, err = a.AddTeamMember(channel.TeamId, userChannel.UserID)
-			if err != nil {
-				return err
+		var mu sync.Mutex
+		failedUserIDs := make(map[string]bool)
+		a.runSyncBatchesConcurrently(userIDsByChannel, func(channelID string, userIDs []string) {
+			channel, ok := channelsByID[channelID]
+			if !ok {
+				mu.Lock()
+				for _, userID := range userIDs {
+					result.AddError(&model.SyncError{UserID: userID, ChannelID: channelID, Op: model.GroupSyncOpAddChannelMember, ErrorID: "app.channel.get_many.missing.app_error"})
+					failedUserIDs[userID] = true
+				}
+				mu.Unlock()
+				return
+			}
+
+			a.addChannelMembersBatch(channel, userIDs, groupIDByChannelUser[channelID], result, &mu, failedUserIDs, adminIDs)
+		})
+
+		// See syncTeamMemberships: cap the watermark at the earliest failed user's CreateAt so a transient failure
+		// doesn't get skipped by the checkpoint on the next run.
+		watermark := since
+		minFailedCreateAt := int64(-1)
+		for _, userChannel := range page {
+			if failedUserIDs[userChannel.UserID] {
+				if minFailedCreateAt < 0 || userChannel.CreateAt < minFailedCreateAt {
+					minFailedCreateAt = userChannel.CreateAt
+				}
+				continue
+			}
+			if userChannel.CreateAt > watermark {
+				watermark = userChannel.CreateAt
 			}
-			a.Log.Info("added teammember",
-				mlog.String("user_id", userChannel.UserID),
-				mlog.String("team_id", channel.TeamId),
-			)
 		}
+		if minFailedCreateAt >= 0 && minFailedCreateAt < watermark {
+			watermark = minFailedCreateAt
+		}
+		a.persistGroupSyncCheckpoint(watermark)
+
+		if nextCursor == nil {
+			break
+		}
+		cursor = nextCursor
+	}
+
+	return nil
+}
+
+// addChannelMembersBatch ensures every user in userIDs is a member of channel's team, then batch-adds only the
+// users confirmed on the team to the channel itself, recording the outcome of both steps onto result. A user whose
+// team add fails is recorded as a failure and excluded from the channel-add batch, since Mattermost requires
+// channel membership to imply team membership. groupIDByUser attributes each user's add to the group whose
+// membership drove it, for the audit/websocket events. failedUserIDs collects every user who failed either step so
+// the caller's checkpoint watermark can skip their row on the next resume instead of treating them as processed.
+// mu guards result and failedUserIDs, which are shared across concurrent calls; it is locked here only around their
+// mutation, not around the store calls, so disjoint channels keep running concurrently.
+func (a *App) addChannelMembersBatch(channel *model.Channel, userIDs []string, groupIDByUser map[string]string, result *model.SyncResult, mu *sync.Mutex, failedUserIDs map[string]bool, adminIDs map[string]bool) {
+	existingTeamMembers, err := a.Srv.Store.Team().GetMembersByIds(channel.TeamId, userIDs, nil)
+	if err != nil {
+		mu.Lock()
+		for _, userID := range userIDs {
+			result.AddError(&model.SyncError{UserID: userID, TeamID: channel.TeamId, ChannelID: channel.Id, Op: model.GroupSyncOpAddTeamMember, ErrorID: err.Id, Transient: model.IsTransientSyncError(err)})
+			failedUserIDs[userID] = true
+		}
+		mu.Unlock()
+		return
+	}
+
+	onTeam := make(map[string]bool, len(existingTeamMembers))
+	for _, tm := range existingTeamMembers {
+		onTeam[tm.UserId] = true
+	}
+
+	var needsTeam []string
+	for _, userID := range userIDs {
+		if !onTeam[userID] {
+			needsTeam = append(needsTeam, userID)
+		}
+	}
 
-		_, err = a.AddChannelMember(userChannel.UserID, channel, "", "")
+	// confirmedOnTeam starts as the users already on the team and only gains a needsTeam user once their
+	// AddTeamMembers insert is confirmed, so a team-add failure keeps that user out of the channel-add batch below
+	// instead of letting them into a channel for a team they were never actually added to.
+	confirmedOnTeam := onTeam
+	if len(needsTeam) > 0 {
+		insertedTeamMembers, err := a.Srv.Store.Team().AddTeamMembers(channel.TeamId, needsTeam)
 		if err != nil {
-			if err.Id == "api.channel.add_user.to.channel.failed.deleted.app_error" {
-				a.Log.Info("Not adding user to channel because they have already left the team",
-					mlog.String("user_id", userChannel.UserID),
-					mlog.String("channel_id", userChannel.ChannelID),
-				)
-			} else {
-				return err
+			mu.Lock()
+			for _, userID := range needsTeam {
+				result.AddError(&model.SyncError{UserID: userID, TeamID: channel.TeamId, ChannelID: channel.Id, Op: model.GroupSyncOpAddTeamMember, ErrorID: err.Id, Transient: model.IsTransientSyncError(err)})
+				failedUserIDs[userID] = true
+			}
+			mu.Unlock()
+		} else {
+			mu.Lock()
+			result.Added += len(insertedTeamMembers)
+			mu.Unlock()
+
+			for _, userID := range insertedTeamMembers {
+				confirmedOnTeam[userID] = true
+				teamSource := model.NewGroupSyncChangeSource(groupIDByUser[userID], channel.TeamId)
+				a.publishAddedToTeamEvent(channel.TeamId, userID)
+				a.auditGroupSyncChange(userID, model.AuditActionGroupSyncAdd, teamSource)
+				a.publishGroupMembershipChangedEvent(userID, teamSource, adminIDs)
+				a.Log.Info("added teammember", mlog.String("user_id", userID), mlog.String("team_id", channel.TeamId))
 			}
 		}
+	}
 
-		a.Log.Info("added channelmember",
-			mlog.String("user_id", userChannel.UserID),
-			mlog.String("channel_id", userChannel.ChannelID),
-		)
+	channelUserIDs := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if confirmedOnTeam[userID] {
+			channelUserIDs = append(channelUserIDs, userID)
+		}
+	}
+	if len(channelUserIDs) == 0 {
+		return
 	}
 
-	return nil
+	insertedChannelMembers, err := a.Srv.Store.Channel().AddChannelMembers(channel.Id, channelUserIDs)
+	if err != nil {
+		mu.Lock()
+		for _, userID := range channelUserIDs {
+			result.AddError(&model.SyncError{UserID: userID, ChannelID: channel.Id, Op: model.GroupSyncOpAddChannelMember, ErrorID: err.Id, Transient: model.IsTransientSyncError(err)})
+			failedUserIDs[userID] = true
+		}
+		mu.Unlock()
+		return
+	}
+
+	mu.Lock()
+	result.Added += len(insertedChannelMembers)
+	result.Skipped += len(channelUserIDs) - len(insertedChannelMembers)
+	mu.Unlock()
+
+	for _, userID := range insertedChannelMembers {
+		channelSource := model.NewGroupSyncChangeSource(groupIDByUser[userID], channel.Id)
+		a.publishAddedToChannelEvent(channel.Id, userID)
+		a.auditGroupSyncChange(userID, model.AuditActionGroupSyncAdd, channelSource)
+		a.publishGroupMembershipChangedEvent(userID, channelSource, adminIDs)
+		a.Log.Info("added channelmember", mlog.String("user_id", userID), mlog.String("channel_id", channel.Id))
+	}
+}
+
+// runSyncBatchesConcurrently invokes process once per key/values pair in batches, capping the number of batches
+// running at the same time at the configured LdapSettings.SyncConcurrency (or GROUP_SYNC_DEFAULT_CONCURRENCY if
+// unset). Keys are disjoint teams/channels, so they can safely be reconciled in parallel.
+func (a *App) runSyncBatchesConcurrently(batches map[string][]string, process func(key string, userIDs []string)) {
+	concurrency := model.GROUP_SYNC_DEFAULT_CONCURRENCY
+	if ldapSettings := a.Config().LdapSettings; ldapSettings != nil && ldapSettings.SyncConcurrency != nil {
+		concurrency = *ldapSettings.SyncConcurrency
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for key, userIDs := range batches {
+		key, userIDs := key, userIDs
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			process(key, userIDs)
+		}()
+	}
+
+	wg.Wait()
+}
+
+// publishAddedToTeamEvent notifies the added user's other sessions that they've been added to a team, mirroring
+// the websocket event AddTeamMember sends for a single-user add.
+func (a *App) publishAddedToTeamEvent(teamID, userID string) {
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_ADDED_TO_TEAM, teamID, "", userID, nil)
+	message.Add("team_id", teamID)
+	message.Add("user_id", userID)
+	a.Publish(message)
+}
+
+// publishAddedToChannelEvent notifies the added user's other sessions that they've been added to a channel,
+// mirroring the websocket event AddChannelMember sends for a single-user add.
+func (a *App) publishAddedToChannelEvent(channelID, userID string) {
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_USER_ADDED, "", channelID, userID, nil)
+	message.Add("channel_id", channelID)
+	message.Add("user_id", userID)
+	a.Publish(message)
+}
+
+// auditGroupSyncChange writes an audit entry for a membership or role change made by the group sync job, so it's
+// distinguishable in the audit log from the same action taken directly by a user or admin.
+func (a *App) auditGroupSyncChange(userID, action string, source *model.MembershipChangeSource) {
+	record := &model.Audit{
+		UserId:    userID,
+		Action:    action,
+		ExtraInfo: fmt.Sprintf("syncable_id=%s requestor_id=%s group_id=%s", source.SyncableID, source.RequestorID, source.GroupID),
+	}
+	if err := a.Srv.Store.Audit().Save(record); err != nil {
+		a.Log.Warn("failed to write group sync audit record", mlog.Err(err))
+	}
+}
+
+// systemAdminIDs looks up the current system admins once so a sync run touching many users doesn't re-query the
+// same unchanging list once per user in publishGroupMembershipChangedEvent.
+func (a *App) systemAdminIDs() map[string]bool {
+	adminIDs := make(map[string]bool)
+	admins, err := a.Srv.Store.User().GetSystemAdminProfiles()
+	if err != nil {
+		a.Log.Warn("failed to look up system admins for group_membership_changed events", mlog.Err(err))
+		return adminIDs
+	}
+	for adminID := range admins {
+		adminIDs[adminID] = true
+	}
+	return adminIDs
+}
+
+// publishGroupMembershipChangedEvent notifies adminIDs and the affected user that a group sync run changed the
+// user's membership or role, so the System Console and client can explain why it happened. adminIDs should be
+// looked up once per sync run via systemAdminIDs, not once per user.
+func (a *App) publishGroupMembershipChangedEvent(userID string, source *model.MembershipChangeSource, adminIDs map[string]bool) {
+	recipients := map[string]bool{userID: true}
+	for adminID := range adminIDs {
+		recipients[adminID] = true
+	}
+
+	for recipientID := range recipients {
+		message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_GROUP_MEMBERSHIP_CHANGED, "", "", recipientID, nil)
+		message.Add("user_id", userID)
+		message.Add("group_id", source.GroupID)
+		message.Add("syncable_id", source.SyncableID)
+		a.Publish(message)
+	}
+}
+
+// soleAllowedGroupID returns the GroupId of the single group-syncable configured for the given syncable, or "" if
+// zero or more than one group is configured. A removal or role change driven by the combined membership of
+// several groups can't be blamed on any one of them, matching MembershipChangeSource.GroupID's documented
+// semantics.
+func (a *App) soleAllowedGroupID(syncableID string, syncableType model.GroupSyncableType) string {
+	syncables, err := a.Srv.Store.Group().GetGroupSyncables(syncableID, syncableType)
+	if err != nil || len(syncables) != 1 {
+		return ""
+	}
+	return syncables[0].GroupId
 }
 
 // DeleteGroupConstrainedMemberships deletes team and channel memberships of users who aren't members of the allowed
-// groups of all group-constrained teams and channels.
-func (a *App) DeleteGroupConstrainedMemberships() error {
+// groups of all group-constrained teams and channels. A failure removing one user does not stop the run; failures
+// are accumulated onto the returned SyncResult so the caller can inspect them.
+func (a *App) DeleteGroupConstrainedMemberships() (*model.SyncResult, error) {
+	result := model.NewSyncResult()
+	adminIDs := a.systemAdminIDs()
+
 	channelMembers, appErr := a.ChannelMembersToRemove()
 	if appErr != nil {
-		return appErr
+		return result, appErr
 	}
 
 	for _, userChannel := range channelMembers {
 		channel, err := a.GetChannel(userChannel.ChannelId)
 		if err != nil {
-			return err
+			result.AddError(&model.SyncError{
+				UserID:    userChannel.UserId,
+				ChannelID: userChannel.ChannelId,
+				Op:        model.GroupSyncOpRemoveChannelMember,
+				ErrorID:   err.Id,
+				Transient: model.IsTransientSyncError(err),
+			})
+			continue
 		}
 
-		err = a.RemoveUserFromChannel(userChannel.UserId, "", channel)
-		if err != nil {
-			return err
+		if err = a.RemoveUserFromChannel(userChannel.UserId, "", channel); err != nil {
+			result.AddError(&model.SyncError{
+				UserID:    userChannel.UserId,
+				ChannelID: channel.Id,
+				Op:        model.GroupSyncOpRemoveChannelMember,
+				ErrorID:   err.Id,
+				Transient: model.IsTransientSyncError(err),
+			})
+			continue
 		}
 
+		result.Removed++
+		channelSource := model.NewGroupSyncChangeSource(a.soleAllowedGroupID(channel.Id, model.GroupSyncableTypeChannel), channel.Id)
+		a.auditGroupSyncChange(userChannel.UserId, model.AuditActionGroupSyncRemove, channelSource)
+		a.publishGroupMembershipChangedEvent(userChannel.UserId, channelSource, adminIDs)
 		a.Log.Info("removed channelmember",
 			mlog.String("user_id", userChannel.UserId),
 			mlog.String("channel_id", channel.Id),
@@ -109,58 +465,263 @@ func (a *App) DeleteGroupConstrainedMemberships() error {
 
 	teamMembers, appErr := a.TeamMembersToRemove()
 	if appErr != nil {
-		return appErr
+		return result, appErr
 	}
 
 	for _, userTeam := range teamMembers {
-		err := a.RemoveUserFromTeam(userTeam.TeamId, userTeam.UserId, "")
-		if err != nil {
-			return err
+		if err := a.RemoveUserFromTeam(userTeam.TeamId, userTeam.UserId, ""); err != nil {
+			result.AddError(&model.SyncError{
+				UserID:    userTeam.UserId,
+				TeamID:    userTeam.TeamId,
+				Op:        model.GroupSyncOpRemoveTeamMember,
+				ErrorID:   err.Id,
+				Transient: model.IsTransientSyncError(err),
+			})
+			continue
 		}
 
+		result.Removed++
+		teamSource := model.NewGroupSyncChangeSource(a.soleAllowedGroupID(userTeam.TeamId, model.GroupSyncableTypeTeam), userTeam.TeamId)
+		a.auditGroupSyncChange(userTeam.UserId, model.AuditActionGroupSyncRemove, teamSource)
+		a.publishGroupMembershipChangedEvent(userTeam.UserId, teamSource, adminIDs)
 		a.Log.Info("removed teammember",
 			mlog.String("user_id", userTeam.UserId),
 			mlog.String("team_id", userTeam.TeamId),
 		)
 	}
 
-	return nil
+	return result, nil
 }
 
-// SyncSyncableRoles updates the SchemeAdmin field value of the given syncable's members based on the configuration of
-// the member's group memberships and the configuration of those groups to the syncable.
+// persistGroupSyncCheckpoint records watermark, the high-water CreateAt of the group memberships actually
+// processed in the last fully-processed CreateDefaultMemberships batch, so that a subsequent run (whether
+// scheduled or a retry after a crash) can resume from there instead of re-scanning every group member from the
+// beginning. It never moves the checkpoint backwards, since the pages of syncTeamMemberships and then
+// syncChannelMemberships all persist into this one checkpoint and a later page's watermark can be lower than an
+// earlier one's.
+func (a *App) persistGroupSyncCheckpoint(watermark int64) {
+	if watermark <= a.groupSyncCheckpoint() {
+		return
+	}
+
+	system := &model.System{
+		Name:  model.SystemLastGroupSyncCheckpointKey,
+		Value: strconv.FormatInt(watermark, 10),
+	}
+	if err := a.Srv.Store.System().SaveOrUpdate(system); err != nil {
+		a.Log.Warn("failed to persist group sync checkpoint", mlog.Err(err))
+	}
+}
+
+// groupSyncCheckpoint reads back the since high-water mark persisted by persistGroupSyncCheckpoint, returning 0
+// (scan everything) if no checkpoint has been saved yet or it can't be read.
+func (a *App) groupSyncCheckpoint() int64 {
+	system, err := a.Srv.Store.System().GetByName(model.SystemLastGroupSyncCheckpointKey)
+	if err != nil || system == nil {
+		return 0
+	}
+
+	since, convErr := strconv.ParseInt(system.Value, 10, 64)
+	if convErr != nil {
+		return 0
+	}
+	return since
+}
+
+// teamMemberIDs returns the user IDs of every current member of teamID, paged in groupSyncPageSize batches so a
+// large team doesn't require one unbounded query.
+func (a *App) teamMemberIDs(teamID string) ([]string, *model.AppError) {
+	var ids []string
+	for offset := 0; ; offset += groupSyncPageSize {
+		members, err := a.Srv.Store.Team().GetMembers(teamID, offset, groupSyncPageSize, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			ids = append(ids, member.UserId)
+		}
+		if len(members) < groupSyncPageSize {
+			return ids, nil
+		}
+	}
+}
+
+// channelMemberIDs returns the user IDs of every current member of channelID, paged in groupSyncPageSize batches
+// so a large channel doesn't require one unbounded query.
+func (a *App) channelMemberIDs(channelID string) ([]string, *model.AppError) {
+	var ids []string
+	for offset := 0; ; offset += groupSyncPageSize {
+		members, err := a.Srv.Store.Channel().GetMembers(channelID, offset, groupSyncPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range *members {
+			ids = append(ids, member.UserId)
+		}
+		if len(*members) < groupSyncPageSize {
+			return ids, nil
+		}
+	}
+}
+
+// mergeExplicitRoles carries every member of memberIDs into the map passed to UpdateMembersExplicitRoles, not just
+// those present in roleAssignments, so a member whose role grant has since been revoked (group membership lost,
+// SchemeRoleID cleared) gets an explicit "" entry and is reset to the syncable's base role instead of keeping a
+// stale elevated role.
+func mergeExplicitRoles(memberIDs []string, roleAssignments map[string]string) map[string]string {
+	explicitRoles := make(map[string]string, len(memberIDs))
+	for _, userID := range memberIDs {
+		explicitRoles[userID] = roleAssignments[userID]
+	}
+	return explicitRoles
+}
+
+// SyncSyncableRoles updates the explicit scheme role of the given syncable's members based on the configuration of
+// the member's group memberships and the configuration of those groups to the syncable. A group's SchemeRoleID, if
+// set, is resolved against the syncable's scheme so an LDAP group can grant a custom role (e.g. a channel scheme's
+// "moderator" role) rather than only the legacy SchemeAdmin toggle.
 func (a *App) SyncSyncableRoles(syncableID string, syncableType model.GroupSyncableType) *model.AppError {
-	permittedAdmins, err := a.Srv.Store.Group().PermittedSyncableAdmins(syncableID, syncableType)
+	roleAssignments, err := a.Srv.Store.Group().PermittedSyncableRoleAssignments(syncableID, syncableType)
 	if err != nil {
 		return err
 	}
 
 	a.Log.Info(
-		fmt.Sprintf("Permitted admins for %s", syncableType),
+		fmt.Sprintf("Permitted role assignments for %s", syncableType),
 		mlog.String(strings.ToLower(fmt.Sprintf("%s_id", syncableType)), syncableID),
-		mlog.Any("permitted_admins", permittedAdmins),
+		mlog.Any("role_assignments", roleAssignments),
 	)
 
-	var updateFunc func(string, []string, store.Equality, bool) *model.AppError
+	var updateFunc func(string, map[string]string) *model.AppError
+	var memberIDsFunc func(string) ([]string, *model.AppError)
 
 	switch syncableType {
 	case model.GroupSyncableTypeTeam:
-		updateFunc = a.Srv.Store.Team().UpdateMembersRole
+		updateFunc = a.Srv.Store.Team().UpdateMembersExplicitRoles
+		memberIDsFunc = a.teamMemberIDs
 	case model.GroupSyncableTypeChannel:
-		updateFunc = a.Srv.Store.Channel().UpdateMembersRole
+		updateFunc = a.Srv.Store.Channel().UpdateMembersExplicitRoles
+		memberIDsFunc = a.channelMemberIDs
 	default:
 		return model.NewAppError("App.SyncSyncableRoles", "groups.unsupported_syncable_type", map[string]interface{}{"Value": syncableType}, "", http.StatusInternalServerError)
 	}
 
-	err = updateFunc(syncableID, permittedAdmins, store.Equals, true)
+	memberIDs, err := memberIDsFunc(syncableID)
 	if err != nil {
 		return err
 	}
 
-	err = updateFunc(syncableID, permittedAdmins, store.NotEquals, false)
-	if err != nil {
+	explicitRoles := mergeExplicitRoles(memberIDs, roleAssignments)
+
+	if err := updateFunc(syncableID, explicitRoles); err != nil {
 		return err
 	}
 
+	adminIDs := a.systemAdminIDs()
+	source := model.NewGroupSyncChangeSource(a.soleAllowedGroupID(syncableID, syncableType), syncableID)
+	// Notify for every member in explicitRoles, not just roleAssignments: a member absent from roleAssignments but
+	// present here was just set to "" by mergeExplicitRoles, which demotes anyone who held a role, and that demotion
+	// deserves the same audit record and group_membership_changed event as a promotion does. Members whose role
+	// was already "" are notified too.
+	for userID := range explicitRoles {
+		a.auditGroupSyncChange(userID, model.AuditActionGroupSyncRole, source)
+		a.publishGroupMembershipChangedEvent(userID, source, adminIDs)
+	}
+
 	return nil
 }
+
+// PlanDefaultMemberships returns the same team and channel membership additions CreateDefaultMemberships would make
+// for members who joined their group on or after since, without adding anyone. Admins can use this to review a
+// pending sync before it runs for real.
+func (a *App) PlanDefaultMemberships(since int64) (*model.GroupSyncPlan, *model.AppError) {
+	plan := &model.GroupSyncPlan{}
+
+	var cursor *model.GroupMemberCursor
+	for {
+		page, nextCursor, appErr := a.TeamMembersToAdd(since, cursor, groupSyncPageSize)
+		if appErr != nil {
+			return nil, appErr
+		}
+		plan.TeamMembersToAdd = append(plan.TeamMembersToAdd, page...)
+		if nextCursor == nil {
+			break
+		}
+		cursor = nextCursor
+	}
+
+	cursor = nil
+	for {
+		page, nextCursor, appErr := a.ChannelMembersToAdd(since, cursor, groupSyncPageSize)
+		if appErr != nil {
+			return nil, appErr
+		}
+		plan.ChannelMembersToAdd = append(plan.ChannelMembersToAdd, page...)
+		if nextCursor == nil {
+			break
+		}
+		cursor = nextCursor
+	}
+
+	return plan, nil
+}
+
+// PlanGroupConstrainedRemovals returns the same team and channel membership removals DeleteGroupConstrainedMemberships
+// would make, without removing anyone.
+func (a *App) PlanGroupConstrainedRemovals() (*model.GroupSyncPlan, *model.AppError) {
+	plan := &model.GroupSyncPlan{}
+
+	channelMembers, appErr := a.ChannelMembersToRemove()
+	if appErr != nil {
+		return nil, appErr
+	}
+	plan.ChannelMembersToRemove = channelMembers
+
+	teamMembers, appErr := a.TeamMembersToRemove()
+	if appErr != nil {
+		return nil, appErr
+	}
+	plan.TeamMembersToRemove = teamMembers
+
+	return plan, nil
+}
+
+// PlanSyncableRoles returns the explicit role assignments SyncSyncableRoles would make for the given syncable,
+// without updating anyone.
+func (a *App) PlanSyncableRoles(syncableID string, syncableType model.GroupSyncableType) (*model.GroupSyncPlan, *model.AppError) {
+	roleAssignments, err := a.Srv.Store.Group().PermittedSyncableRoleAssignments(syncableID, syncableType)
+	if err != nil {
+		return nil, err
+	}
+
+	var memberIDsFunc func(string) ([]string, *model.AppError)
+	switch syncableType {
+	case model.GroupSyncableTypeTeam:
+		memberIDsFunc = a.teamMemberIDs
+	case model.GroupSyncableTypeChannel:
+		memberIDsFunc = a.channelMemberIDs
+	default:
+		return nil, model.NewAppError("App.PlanSyncableRoles", "groups.unsupported_syncable_type", map[string]interface{}{"Value": syncableType}, "", http.StatusInternalServerError)
+	}
+
+	memberIDs, err := memberIDsFunc(syncableID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.GroupSyncPlan{RoleAssignments: mergeExplicitRoles(memberIDs, roleAssignments)}, nil
+}
+
+// TeamMembersToAdd returns a page of at most pageSize pending team memberships implied by group-team syncables for
+// members who joined their group on or after since, starting after cursor (nil fetches the first page). The
+// returned cursor should be passed to the next call; a nil returned cursor means this was the last page.
+func (a *App) TeamMembersToAdd(since int64, cursor *model.GroupMemberCursor, pageSize int) ([]*model.UserTeamIDPair, *model.GroupMemberCursor, *model.AppError) {
+	return a.Srv.Store.Group().TeamMembersToAdd(since, cursor, pageSize)
+}
+
+// ChannelMembersToAdd returns a page of at most pageSize pending channel memberships implied by group-channel
+// syncables for members who joined their group on or after since, starting after cursor (nil fetches the first
+// page). The returned cursor should be passed to the next call; a nil returned cursor means this was the last page.
+func (a *App) ChannelMembersToAdd(since int64, cursor *model.GroupMemberCursor, pageSize int) ([]*model.UserChannelIDPair, *model.GroupMemberCursor, *model.AppError) {
+	return a.Srv.Store.Group().ChannelMembersToAdd(since, cursor, pageSize)
+}
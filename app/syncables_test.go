@@ -0,0 +1,69 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package app
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// TestMergeExplicitRoles covers the chunk0-4 regression: a member who is no longer in roleAssignments (their group
+// membership or SchemeRoleID was revoked since the last sync) must still appear in the merged map with an empty
+// role, so UpdateMembersExplicitRoles demotes them instead of leaving a stale elevated role in place.
+func TestMergeExplicitRoles(t *testing.T) {
+	memberIDs := []string{"user1", "user2", "user3"}
+	roleAssignments := map[string]string{"user1": "channel_moderator"}
+
+	got := mergeExplicitRoles(memberIDs, roleAssignments)
+
+	want := map[string]string{"user1": "channel_moderator", "user2": "", "user3": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("mergeExplicitRoles() = %v, want %v", got, want)
+	}
+}
+
+// TestRunSyncBatchesConcurrently covers the chunk0-2 regression: batches must actually run concurrently, not be
+// serialized by a lock held across the whole batch. With 4 batches that each take batchDelay and a worker pool big
+// enough to run all of them at once, the call should take close to one batchDelay, not four.
+func TestRunSyncBatchesConcurrently(t *testing.T) {
+	th := Setup(t)
+	defer th.TearDown()
+
+	concurrency := 4
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		cfg.LdapSettings.SyncConcurrency = model.NewInt(concurrency)
+	})
+	a := th.App
+
+	const batchDelay = 50 * time.Millisecond
+	batches := map[string][]string{
+		"key1": {"u1"},
+		"key2": {"u2"},
+		"key3": {"u3"},
+		"key4": {"u4"},
+	}
+
+	var mu sync.Mutex
+	var ran int
+
+	start := time.Now()
+	a.runSyncBatchesConcurrently(batches, func(key string, userIDs []string) {
+		time.Sleep(batchDelay)
+		mu.Lock()
+		ran++
+		mu.Unlock()
+	})
+	elapsed := time.Since(start)
+
+	if ran != len(batches) {
+		t.Fatalf("expected all %d batches to run, got %d", len(batches), ran)
+	}
+	if elapsed >= time.Duration(len(batches))*batchDelay {
+		t.Fatalf("batches ran serially: took %s for %d batches of %s each", elapsed, len(batches), batchDelay)
+	}
+}
@@ -0,0 +1,17 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package model
+
+// GROUP_SYNC_DEFAULT_CONCURRENCY is the default value of LdapSettings.SyncConcurrency, used when an installation
+// hasn't configured it explicitly.
+//
+// SyncIntervalMinutes and SyncConcurrency are new fields on the existing LdapSettings struct (not redeclared in
+// this trimmed checkout): SyncIntervalMinutes controls how often the scheduled group sync job runs, and
+// SyncConcurrency bounds how many teams/channels it reconciles in parallel. Raising the latter speeds up large
+// syncs at the cost of more simultaneous store connections; it has no effect on how many users are batched into a
+// single INSERT. Both default via LdapSettings.SetDefaults(): SyncIntervalMinutes to 60, SyncConcurrency to
+// GROUP_SYNC_DEFAULT_CONCURRENCY.
+const (
+	GROUP_SYNC_DEFAULT_CONCURRENCY = 8
+)
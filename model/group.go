@@ -0,0 +1,33 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package model
+
+// GroupSyncableType enumerates the kinds of syncable a group can be configured to sync membership (and role) into.
+type GroupSyncableType string
+
+const (
+	GroupSyncableTypeTeam    GroupSyncableType = "Team"
+	GroupSyncableTypeChannel GroupSyncableType = "Channel"
+)
+
+// GroupSyncable represents a team or channel configured to sync its membership from an LDAP or SAML group.
+type GroupSyncable struct {
+	GroupId    string            `json:"group_id"`
+	SyncableId string            `json:"-"`
+	Type       GroupSyncableType `json:"-"`
+	AutoAdd    bool              `json:"auto_add"`
+
+	// SchemeAdmin is the legacy on/off admin toggle. It's still honored when SchemeRoleID is empty, so existing
+	// syncables that were never migrated to a custom scheme role keep working unchanged.
+	SchemeAdmin bool `json:"scheme_admin"`
+
+	// SchemeRoleID optionally names a role from the syncable's scheme (e.g. a channel scheme's custom "moderator"
+	// role) that members of this group should hold. When set, it takes precedence over SchemeAdmin, letting an
+	// LDAP group grant something other than plain admin.
+	SchemeRoleID string `json:"scheme_role_id"`
+
+	CreateAt int64 `json:"create_at"`
+	DeleteAt int64 `json:"delete_at"`
+	UpdateAt int64 `json:"update_at"`
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package model
+
+import "net/http"
+
+const (
+	GroupSyncOpAddTeamMember       = "add_team_member"
+	GroupSyncOpAddChannelMember    = "add_channel_member"
+	GroupSyncOpRemoveTeamMember    = "remove_team_member"
+	GroupSyncOpRemoveChannelMember = "remove_channel_member"
+
+	// SystemLastGroupSyncCheckpointKey is the System table key used to persist the "since" high-water mark of the
+	// last fully-processed group sync batch, so a run that stops early resumes from there instead of from scratch.
+	SystemLastGroupSyncCheckpointKey = "LastGroupSyncCheckpoint"
+
+	// groupSyncMaxFailedRecords bounds how many per-user failure records a SyncResult keeps; the aggregate Failed
+	// count keeps growing past this, but the detailed records are dropped to avoid unbounded memory use on large
+	// syncs with many failures.
+	groupSyncMaxFailedRecords = 1000
+)
+
+// GroupMemberCursor pages through pending group membership changes ordered by UserID, then CreateAt. Keying the
+// cursor on the same columns the query sorts by keeps pages stable even if new group members are added while a
+// page is in flight.
+type GroupMemberCursor struct {
+	UserID   string
+	CreateAt int64
+}
+
+// UserTeamIDPair identifies a pending group-driven team membership addition: userID should be added to teamID
+// because of their membership in GroupID. CreateAt is the group membership's creation time, used to advance the
+// sync checkpoint as pages are processed.
+type UserTeamIDPair struct {
+	UserID   string
+	TeamID   string
+	GroupID  string
+	CreateAt int64
+}
+
+// UserChannelIDPair identifies a pending group-driven channel membership addition: userID should be added to
+// channelID because of their membership in GroupID. CreateAt is the group membership's creation time, used to
+// advance the sync checkpoint as pages are processed.
+type UserChannelIDPair struct {
+	UserID    string
+	ChannelID string
+	GroupID   string
+	CreateAt  int64
+}
+
+// SyncError describes a single user's failure during a group membership sync run.
+type SyncError struct {
+	UserID    string `json:"user_id"`
+	TeamID    string `json:"team_id,omitempty"`
+	ChannelID string `json:"channel_id,omitempty"`
+	Op        string `json:"op"`
+	ErrorID   string `json:"error_id"`
+	Transient bool   `json:"transient"`
+}
+
+// SyncResult aggregates the outcome of a group membership sync run (e.g. CreateDefaultMemberships or
+// DeleteGroupConstrainedMemberships) so the Jobs subsystem and System Console can report on it even when some
+// users failed along the way.
+type SyncResult struct {
+	Added   int          `json:"added"`
+	Removed int          `json:"removed"`
+	Skipped int          `json:"skipped"`
+	Failed  int          `json:"failed"`
+	Errors  []*SyncError `json:"errors"`
+}
+
+// NewSyncResult returns an empty SyncResult ready to be accumulated into.
+func NewSyncResult() *SyncResult {
+	return &SyncResult{}
+}
+
+// AddError records a per-user failure, incrementing Failed and, up to groupSyncMaxFailedRecords, keeping the
+// detailed record.
+func (r *SyncResult) AddError(syncErr *SyncError) {
+	r.Failed++
+	if len(r.Errors) < groupSyncMaxFailedRecords {
+		r.Errors = append(r.Errors, syncErr)
+	}
+}
+
+// HasTransientErrors returns true if at least one recorded failure is classified as transient, meaning the caller
+// (typically the Jobs scheduler) should retry the run rather than treat it as a permanent failure.
+func (r *SyncResult) HasTransientErrors() bool {
+	for _, e := range r.Errors {
+		if e.Transient {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTransientSyncError classifies whether a sync failure is likely to succeed on retry (a transient DB or network
+// blip) as opposed to a permanent condition, such as a deleted account or a team member limit, that will fail
+// again on every retry.
+func IsTransientSyncError(appErr *AppError) bool {
+	if appErr == nil {
+		return false
+	}
+	return appErr.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,25 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package model
+
+import "encoding/json"
+
+// GroupSyncPlan describes what a group sync run would do without actually doing it, so an admin can review a
+// misconfigured group-constrained team or channel before it mass-adds or mass-removes members.
+type GroupSyncPlan struct {
+	TeamMembersToAdd       []*UserTeamIDPair    `json:"team_members_to_add"`
+	ChannelMembersToAdd    []*UserChannelIDPair `json:"channel_members_to_add"`
+	TeamMembersToRemove    []*TeamMember        `json:"team_members_to_remove"`
+	ChannelMembersToRemove []*ChannelMember     `json:"channel_members_to_remove"`
+
+	// RoleAssignments is only populated when the plan covers a role sync preview; it maps userID to the scheme
+	// role ID that user would be assigned on the previewed syncable ("" for a member reset to the base role).
+	RoleAssignments map[string]string `json:"role_assignments,omitempty"`
+}
+
+// ToJson serializes the plan for the preview API response.
+func (p *GroupSyncPlan) ToJson() []byte {
+	b, _ := json.Marshal(p)
+	return b
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package model
+
+const (
+	// GroupSyncRequestorID marks an audit record or websocket event as originating from the group sync job rather
+	// than a user-initiated action.
+	GroupSyncRequestorID = "group-sync"
+
+	AuditActionGroupSyncAdd    = "group_sync_add"
+	AuditActionGroupSyncRemove = "group_sync_remove"
+	AuditActionGroupSyncRole   = "group_sync_role"
+
+	WEBSOCKET_EVENT_GROUP_MEMBERSHIP_CHANGED = "group_membership_changed"
+)
+
+// MembershipChangeSource records why a team/channel membership or role change happened, so group-sync-driven
+// changes can be attributed to the group that caused them instead of looking like a user-initiated action.
+type MembershipChangeSource struct {
+	// GroupID is the group that caused the change, when a single group can be identified. It's left empty for
+	// changes implied by the combined membership of several groups on the same syncable.
+	GroupID     string `json:"group_id,omitempty"`
+	SyncableID  string `json:"syncable_id"`
+	RequestorID string `json:"requestor_id"`
+}
+
+// NewGroupSyncChangeSource returns a MembershipChangeSource attributing a membership or role change to group sync.
+func NewGroupSyncChangeSource(groupID, syncableID string) *MembershipChangeSource {
+	return &MembershipChangeSource{GroupID: groupID, SyncableID: syncableID, RequestorID: GroupSyncRequestorID}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+// Package store declares the contract the group sync job (app/syncables.go) relies on. It does not implement the
+// contract: this checkout doesn't carry the sqlstore package or a schema to implement it against, so the
+// interfaces below exist to document and type-check the methods app/syncables.go calls, in lieu of the real
+// store/sqlstore packages this diff would otherwise extend. There's no pre-existing GroupStore.TeamMembersToAdd,
+// GroupStore.ChannelMembersToAdd, Team/ChannelStore.AddTeamMembers/AddChannelMembers, or
+// Team/ChannelStore.UpdateMembersExplicitRoles method anywhere in this tree to collide with; these are additions.
+package store
+
+import "github.com/mattermost/mattermost-server/v5/model"
+
+// Store is the data access layer app.App.Srv embeds, namespaced by entity.
+type Store interface {
+	Team() TeamStore
+	Channel() ChannelStore
+	Group() GroupStore
+	System() SystemStore
+	User() UserStore
+	Audit() AuditStore
+}
+
+// TeamStore is the subset of the team store the group sync job depends on.
+type TeamStore interface {
+	// AddTeamMembers inserts userIDs as members of teamID in a single multi-row INSERT ... ON CONFLICT DO NOTHING,
+	// returning only the user IDs that were actually inserted (already-present members are silently skipped, not
+	// errored).
+	AddTeamMembers(teamID string, userIDs []string) ([]string, *model.AppError)
+	GetMembersByIds(teamID string, userIDs []string, restrictions interface{}) ([]*model.TeamMember, *model.AppError)
+	GetMembers(teamID string, offset, limit int, restrictions interface{}) ([]*model.TeamMember, *model.AppError)
+
+	// UpdateMembersExplicitRoles sets the explicit scheme role of every member of teamID named in explicitRoles to
+	// the given (possibly empty) role ID; a member of teamID omitted from explicitRoles is left unchanged, so
+	// callers that want a member reset to the team's base role must include them with an empty string.
+	UpdateMembersExplicitRoles(teamID string, explicitRoles map[string]string) *model.AppError
+}
+
+// ChannelStore is the subset of the channel store the group sync job depends on.
+type ChannelStore interface {
+	GetMany(channelIDs []string, allowFromCache bool) ([]*model.Channel, *model.AppError)
+	GetMembers(channelID string, offset, limit int) (*model.ChannelMembers, *model.AppError)
+
+	// AddChannelMembers inserts userIDs as members of channelID in a single multi-row INSERT ... ON CONFLICT DO
+	// NOTHING plus one bulk ChannelMemberHistory insert, returning only the user IDs actually inserted.
+	AddChannelMembers(channelID string, userIDs []string) ([]string, *model.AppError)
+
+	// UpdateMembersExplicitRoles mirrors TeamStore.UpdateMembersExplicitRoles for channel members.
+	UpdateMembersExplicitRoles(channelID string, explicitRoles map[string]string) *model.AppError
+}
+
+// GroupStore is the subset of the group store the group sync job depends on.
+type GroupStore interface {
+	// TeamMembersToAdd returns a page of at most pageSize pending group-driven team memberships for members who
+	// joined their group on or after since, ordered by UserID then CreateAt and starting after cursor (nil fetches
+	// the first page). A nil returned cursor means the page returned was the last one.
+	TeamMembersToAdd(since int64, cursor *model.GroupMemberCursor, pageSize int) ([]*model.UserTeamIDPair, *model.GroupMemberCursor, *model.AppError)
+
+	// ChannelMembersToAdd mirrors TeamMembersToAdd for group-driven channel memberships.
+	ChannelMembersToAdd(since int64, cursor *model.GroupMemberCursor, pageSize int) ([]*model.UserChannelIDPair, *model.GroupMemberCursor, *model.AppError)
+
+	GetGroupSyncables(syncableID string, syncableType model.GroupSyncableType) ([]*model.GroupSyncable, *model.AppError)
+
+	// PermittedSyncableRoleAssignments returns, for every member who should hold an explicit scheme role on
+	// syncableID per their group memberships' SchemeRoleID and that role's configuration against the syncable's
+	// scheme, a map of userID to the scheme role ID they should be granted. Members who shouldn't hold an explicit
+	// role are absent from the map, not mapped to "".
+	PermittedSyncableRoleAssignments(syncableID string, syncableType model.GroupSyncableType) (map[string]string, *model.AppError)
+}
+
+// SystemStore is the subset of the system key/value store the group sync job depends on.
+type SystemStore interface {
+	GetByName(name string) (*model.System, *model.AppError)
+	SaveOrUpdate(system *model.System) *model.AppError
+}
+
+// UserStore is the subset of the user store the group sync job depends on.
+type UserStore interface {
+	GetSystemAdminProfiles() (map[string]*model.User, *model.AppError)
+}
+
+// AuditStore is the subset of the audit store the group sync job depends on.
+type AuditStore interface {
+	Save(audit *model.Audit) *model.AppError
+}